@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"path"
+	"strings"
+)
+
+// ACL restricts which nicks or hostmasks may invoke a command
+type ACL struct {
+	nicks     map[string]bool
+	hostmasks []string
+}
+
+// NewACL creates an empty ACL; without any Allow* calls it denies everyone
+func NewACL() *ACL {
+	return &ACL{nicks: make(map[string]bool)}
+}
+
+// AllowNick grants nick access and returns the ACL for chaining
+func (a *ACL) AllowNick(nick string) *ACL {
+	a.nicks[nick] = true
+	return a
+}
+
+// AllowHostmask grants access to every nick whose hostmask matches pattern.
+// pattern uses path.Match syntax and is matched against the bare "user@host"
+// form (e.g. "*@*.staff.example.net"), not "nick!user@host" -- IRCCloud's
+// buffer_msg events only ever carry the former in from_host. Any "nick!"
+// prefix present in the value passed to Allows is stripped before matching,
+// so patterns don't need to account for it either way.
+func (a *ACL) AllowHostmask(pattern string) *ACL {
+	a.hostmasks = append(a.hostmasks, pattern)
+	return a
+}
+
+// Allows reports whether nick / hostmask may invoke the command the ACL is attached to.
+// A nil ACL allows everyone.
+func (a *ACL) Allows(nick, hostmask string) bool {
+	if a == nil {
+		return true
+	}
+
+	if a.nicks[nick] {
+		return true
+	}
+
+	hostmask = stripNickPrefix(hostmask)
+	for _, pattern := range a.hostmasks {
+		if matched, _ := path.Match(pattern, hostmask); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripNickPrefix drops a leading "nick!" from a full "nick!user@host"
+// hostmask, leaving bare "user@host" hostmasks (as carried by from_host) untouched
+func stripNickPrefix(hostmask string) string {
+	if idx := strings.IndexByte(hostmask, '!'); idx >= 0 {
+		return hostmask[idx+1:]
+	}
+	return hostmask
+}
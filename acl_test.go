@@ -0,0 +1,49 @@
+package bot
+
+import "testing"
+
+func TestACLNilAllowsEveryone(t *testing.T) {
+	var acl *ACL
+	if !acl.Allows("anyone", "anyone@example.net") {
+		t.Fatal("nil ACL should allow everyone")
+	}
+}
+
+func TestACLAllowNick(t *testing.T) {
+	acl := NewACL().AllowNick("alice")
+
+	if !acl.Allows("alice", "alice@example.net") {
+		t.Fatal("expected alice to be allowed")
+	}
+	if acl.Allows("bob", "bob@example.net") {
+		t.Fatal("expected bob to be denied")
+	}
+}
+
+func TestACLAllowHostmask(t *testing.T) {
+	acl := NewACL().AllowHostmask("*@*.staff.example.net")
+
+	if !acl.Allows("alice", "alice@shell.staff.example.net") {
+		t.Fatal("expected hostmask match to be allowed")
+	}
+	if acl.Allows("mallory", "mallory@evil.example.net") {
+		t.Fatal("expected non-matching hostmask to be denied")
+	}
+}
+
+func TestACLAllowHostmaskStripsNickPrefix(t *testing.T) {
+	acl := NewACL().AllowHostmask("*@*.staff.example.net")
+
+	// Full "nick!user@host" hostmasks (e.g. from JoinEvent) must match the
+	// same bare user@host patterns as BufferMsgEvent's from_host-derived ones
+	if !acl.Allows("alice", "alice!~alice@shell.staff.example.net") {
+		t.Fatal("expected nick!user@host hostmask to match after stripping the nick prefix")
+	}
+}
+
+func TestACLDenyWithoutMatchingRule(t *testing.T) {
+	acl := NewACL()
+	if acl.Allows("alice", "alice@example.net") {
+		t.Fatal("ACL with no rules should deny everyone")
+	}
+}
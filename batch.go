@@ -0,0 +1,74 @@
+package bot
+
+// Batch groups the events IRCCloud sent as part of a single IRCv3 batch (e.g.
+// a chathistory replay or a netsplit), so they can be processed atomically
+// instead of one line at a time
+type Batch struct {
+	ID     string
+	Type   string
+	Events []Event
+}
+
+// BatchHandler registers h to be called with the completed Batch once the
+// batch it belongs to closes. Events belonging to an open batch are taken out
+// of the normal event flow and are delivered only as part of their Batch; if
+// no BatchHandler is registered they are not delivered anywhere.
+func (i *IRCCloudBot) BatchHandler(h func(*Batch) error) {
+	i.batchHandlers = append(i.batchHandlers, h)
+}
+
+// collectBatch folds e into an open batch if it belongs to one, reporting
+// whether e was consumed and should not be passed on to the normal event flow
+func (i *IRCCloudBot) collectBatch(e Event) (bool, error) {
+	if typ, _ := e["type"].(string); typ == "batch" {
+		return true, i.handleBatchControl(e)
+	}
+
+	id, ok := parseTags(e)["batch"]
+	if !ok {
+		return false, nil
+	}
+
+	i.streamMu.Lock()
+	b, open := i.openBatches[id]
+	if open {
+		b.Events = append(b.Events, e)
+	}
+	i.streamMu.Unlock()
+
+	return open, nil
+}
+
+// handleBatchControl opens or closes a batch based on an IRCv3 "batch" event.
+// On close it delivers the accumulated Batch to every registered BatchHandler.
+func (i *IRCCloudBot) handleBatchControl(e Event) error {
+	id, _ := e.Str("id")
+	if id == "" {
+		return nil
+	}
+
+	if start, _ := e.Bool("start"); start {
+		batchType, _ := e.Str("batch_type")
+		i.streamMu.Lock()
+		i.openBatches[id] = &Batch{ID: id, Type: batchType}
+		i.streamMu.Unlock()
+		return nil
+	}
+
+	i.streamMu.Lock()
+	b, ok := i.openBatches[id]
+	delete(i.openBatches, id)
+	i.streamMu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	for _, h := range i.batchHandlers {
+		if err := h(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -11,10 +11,12 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"sync"
 	"time"
 
 	"golang.org/x/net/context"
 	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -34,17 +36,45 @@ type IRCCloudBot struct {
 	AutoReconnect       bool
 	YieldInternalEvents bool
 	DropUnhandledEvents bool
+	DisableRawEvents    bool
+
+	// MessagesPerSecond and Burst configure the per-connection outbound token-bucket limiter
+	MessagesPerSecond float64
+	Burst             int
+	// SendMode controls what happens when that limiter is exhausted
+	SendMode SendMode
+
+	// SessionRefreshInterval controls how often the session is validated against
+	// /chat/session in the background; defaults to defaultSessionRefreshInterval if zero
+	SessionRefreshInterval time.Duration
 
 	email, password string
 
-	eventHandlers      map[string][]EventHandler
-	errChan            chan error
-	eventChan          chan Event
-	parentContext      context.Context
-	idleContext        context.Context
-	idleContextCancel  context.CancelFunc
-	idleContextTimeout *time.Timer
-	session            string
+	commands          map[string]*commandRegistration
+	commandMiddleware []CommandMiddleware
+	channelPrefixes   map[string]string
+	defaultPrefix     string
+	commanderOnce     sync.Once
+
+	eventHandlers        map[string][]EventHandler
+	typedHandlers        map[string][]func(interface{}) error
+	batchHandlers        []func(*Batch) error
+	streamMu             sync.Mutex
+	capsByCID            map[int][]string
+	buffers              map[bufferKey]Buffer
+	openBatches          map[string]*Batch
+	sendMu               sync.Mutex
+	limiters             map[int]*rate.Limiter
+	sendLocks            map[string]*sync.Mutex
+	errChan              chan error
+	eventChan            chan Event
+	parentContext        context.Context
+	idleContext          context.Context
+	idleContextCancel    context.CancelFunc
+	idleContextTimeout   *time.Timer
+	session              string
+	sessionMu            sync.RWMutex
+	sessionValidatorOnce sync.Once
 }
 
 // New creates an IRCCloudBot instance with background context and tries to log into IRCCloud with it
@@ -59,12 +89,24 @@ func WithContext(ctx context.Context, email, password string) (*IRCCloudBot, err
 		AutoReconnect:       true,
 		YieldInternalEvents: false,
 		DropUnhandledEvents: false,
+		MessagesPerSecond:   1,
+		Burst:               4,
+		SendMode:            SendModeBlock,
 
 		email:         email,
 		password:      password,
 		parentContext: ctx,
 
+		commands:        make(map[string]*commandRegistration),
+		channelPrefixes: make(map[string]string),
+
 		eventHandlers: make(map[string][]EventHandler),
+		typedHandlers: make(map[string][]func(interface{}) error),
+		capsByCID:     make(map[int][]string),
+		buffers:       make(map[bufferKey]Buffer),
+		openBatches:   make(map[string]*Batch),
+		limiters:      make(map[int]*rate.Limiter),
+		sendLocks:     make(map[string]*sync.Mutex),
 		errChan:       make(chan error),
 		eventChan:     make(chan Event, 100),
 	}
@@ -84,6 +126,8 @@ func (i *IRCCloudBot) Err() error {
 
 // Start starts the stream listening
 func (i *IRCCloudBot) Start() {
+	i.sessionValidatorOnce.Do(func() { go i.validateSessionLoop() })
+
 	i.idleContext, i.idleContextCancel = context.WithCancel(i.parentContext)
 
 	i.idleContextTimeout = time.AfterFunc(idleTimeout, i.idleContextCancel)
@@ -99,34 +143,48 @@ func (i *IRCCloudBot) Start() {
 	}()
 }
 
-// Join joins a channel on the specified connection ID
+// Join joins a channel on the specified connection ID, subject to the same
+// rate limiting and retry behavior as Say
 func (i *IRCCloudBot) Join(connectionID int, channel string) error {
-	return i.authenticatedPost("/chat/join", url.Values{
-		"cid":     []string{strconv.Itoa(connectionID)},
-		"channel": []string{channel},
+	return i.throttledAction(i.parentContext, connectionID, func() error {
+		return i.authenticatedPost("/chat/join", url.Values{
+			"cid":     []string{strconv.Itoa(connectionID)},
+			"channel": []string{channel},
+		})
 	})
 }
 
-// Part leaves a channel on the specified connection ID
+// Part leaves a channel on the specified connection ID, subject to the same
+// rate limiting and retry behavior as Say
 func (i *IRCCloudBot) Part(connectionID int, channel string) error {
-	return i.authenticatedPost("/chat/part", url.Values{
-		"cid":     []string{strconv.Itoa(connectionID)},
-		"channel": []string{channel},
+	return i.throttledAction(i.parentContext, connectionID, func() error {
+		return i.authenticatedPost("/chat/part", url.Values{
+			"cid":     []string{strconv.Itoa(connectionID)},
+			"channel": []string{channel},
+		})
 	})
 }
 
-// Topic sets the topic of a  channel on the specified connection ID
+// Topic sets the topic of a  channel on the specified connection ID, subject
+// to the same rate limiting and retry behavior as Say
 func (i *IRCCloudBot) Topic(connectionID int, channel, topic string) error {
-	return i.authenticatedPost("/chat/topic", url.Values{
-		"cid":     []string{strconv.Itoa(connectionID)},
-		"channel": []string{channel},
-		"topic":   []string{topic},
+	return i.throttledAction(i.parentContext, connectionID, func() error {
+		return i.authenticatedPost("/chat/topic", url.Values{
+			"cid":     []string{strconv.Itoa(connectionID)},
+			"channel": []string{channel},
+			"topic":   []string{topic},
+		})
 	})
 }
 
-// Say posts a message to target on the specified connection ID
+// Say posts a message to target on the specified connection ID, transparently
+// rate limiting, splitting and retrying as configured on the bot
 // Example: mybot.Say(2, "#mychannel", "ohai!")
 func (i *IRCCloudBot) Say(connectionID int, target, message string) error {
+	return i.SendCtx(i.parentContext, connectionID, target, message)
+}
+
+func (i *IRCCloudBot) say(connectionID int, target, message string) error {
 	return i.authenticatedPost("/chat/say", url.Values{
 		"cid": []string{strconv.Itoa(connectionID)},
 		"to":  []string{target},
@@ -134,11 +192,14 @@ func (i *IRCCloudBot) Say(connectionID int, target, message string) error {
 	})
 }
 
-// Nick changes the own nickname on the specified connection ID
+// Nick changes the own nickname on the specified connection ID, subject to
+// the same rate limiting and retry behavior as Say
 func (i *IRCCloudBot) Nick(connectionID int, nick string) error {
-	return i.authenticatedPost("/chat/nick", url.Values{
-		"cid":  []string{strconv.Itoa(connectionID)},
-		"nick": []string{nick},
+	return i.throttledAction(i.parentContext, connectionID, func() error {
+		return i.authenticatedPost("/chat/nick", url.Values{
+			"cid":  []string{strconv.Itoa(connectionID)},
+			"nick": []string{nick},
+		})
 	})
 }
 
@@ -154,17 +215,41 @@ func (i *IRCCloudBot) RegisterMessageHandler(eventType string, eh EventHandler)
 	}
 }
 
+// transientSendError marks an authenticatedPost failure that is worth retrying:
+// a network-level error or a 5xx response from IRCCloud
+type transientSendError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *transientSendError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("transient error talking to IRCCloud: %s", e.Err)
+	}
+	return fmt.Sprintf("transient error talking to IRCCloud: status %d", e.StatusCode)
+}
+
+// Unwrap exposes the underlying network error so errors.Is / errors.As keep working
+// for callers of Join/Part/Topic/Nick/Say that wrap or inspect the returned error
+func (e *transientSendError) Unwrap() error {
+	return e.Err
+}
+
 func (i *IRCCloudBot) authenticatedPost(path string, values url.Values) error {
-	values.Set("session", i.session)
+	values.Set("session", i.getSession())
 
 	req := i.getAuthenticatedRequest("POST", path, bytes.NewBufferString(values.Encode()))
 	req.Header.Set("content-type", "application/x-www-form-urlencoded")
 	res, err := ctxhttp.Do(i.parentContext, i.HTTPClient, req)
 	if err != nil {
-		return err
+		return &transientSendError{Err: err}
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode >= http.StatusInternalServerError {
+		return &transientSendError{StatusCode: res.StatusCode}
+	}
+
 	r := map[string]interface{}{}
 	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
 		return err
@@ -185,6 +270,11 @@ func (i *IRCCloudBot) listenAndParseEvents() error {
 	}
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusUnauthorized {
+		// Session expired, transparently log back in and let the caller reconnect
+		return i.login()
+	}
+
 	lr := bufio.NewScanner(res.Body)
 	for lr.Scan() {
 		e := Event{
@@ -197,6 +287,15 @@ func (i *IRCCloudBot) listenAndParseEvents() error {
 
 		i.idleContextTimeout.Reset(idleTimeout)
 
+		i.captureCapabilities(e)
+		i.captureBuffer(e)
+
+		if consumed, err := i.collectBatch(e); err != nil {
+			return err
+		} else if consumed {
+			continue
+		}
+
 		ih, internallyHandled := internalMessageHandlers[e["type"].(string)]
 		if internallyHandled {
 			if err := ih(e); err != nil {
@@ -204,6 +303,14 @@ func (i *IRCCloudBot) listenAndParseEvents() error {
 			}
 		}
 
+		if err := i.dispatchTypedEvent(e); err != nil {
+			return err
+		}
+
+		if i.DisableRawEvents {
+			continue
+		}
+
 		if !internallyHandled || i.YieldInternalEvents {
 			if ehs, ok := i.eventHandlers[e["type"].(string)]; ok && len(ehs) > 0 {
 				for _, eh := range ehs {
@@ -225,13 +332,25 @@ func (i *IRCCloudBot) listenAndParseEvents() error {
 	return nil
 }
 
+func (i *IRCCloudBot) getSession() string {
+	i.sessionMu.RLock()
+	defer i.sessionMu.RUnlock()
+	return i.session
+}
+
+func (i *IRCCloudBot) setSession(session string) {
+	i.sessionMu.Lock()
+	defer i.sessionMu.Unlock()
+	i.session = session
+}
+
 func (i *IRCCloudBot) getAuthenticatedRequest(method, urlPath string, body io.Reader) *http.Request {
-	if i.session == "" {
+	if i.getSession() == "" {
 		log.Fatalf("Login did not work, session is empty!")
 	}
 
 	req, _ := http.NewRequest(method, "https://www.irccloud.com"+urlPath, body)
-	req.Header.Set("cookie", "session="+i.session)
+	req.Header.Set("cookie", "session="+i.getSession())
 
 	return req
 }
@@ -285,6 +404,6 @@ func (i *IRCCloudBot) login() error {
 		return fmt.Errorf("Login was not successful: %#v", ld)
 	}
 
-	i.session = ld["session"].(string)
+	i.setSession(ld["session"].(string))
 	return nil
 }
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MissingCapabilityError is returned by RequireCaps when the network behind a
+// connection does not advertise one of the requested IRCv3 capabilities
+type MissingCapabilityError struct {
+	CID        int
+	Capability string
+}
+
+// Error implements the error interface
+func (e *MissingCapabilityError) Error() string {
+	return fmt.Sprintf("cid %d does not support capability %q", e.CID, e.Capability)
+}
+
+// Capabilities returns the IRCv3 capabilities the network behind connectionID
+// advertised, as learned from its make_server / server_details events
+func (i *IRCCloudBot) Capabilities(connectionID int) ([]string, error) {
+	i.streamMu.Lock()
+	defer i.streamMu.Unlock()
+
+	caps, ok := i.capsByCID[connectionID]
+	if !ok {
+		return nil, fmt.Errorf("no capability information known for cid %d yet", connectionID)
+	}
+	return caps, nil
+}
+
+// RequireCaps returns a *MissingCapabilityError if the network behind connectionID
+// does not advertise one of caps, or an error if no capability information is
+// known for that connection yet
+func (i *IRCCloudBot) RequireCaps(connectionID int, caps ...string) error {
+	known, err := i.Capabilities(connectionID)
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(known))
+	for _, c := range known {
+		have[c] = true
+	}
+
+	for _, c := range caps {
+		if !have[c] {
+			return &MissingCapabilityError{CID: connectionID, Capability: c}
+		}
+	}
+
+	return nil
+}
+
+// captureCapabilities caches the IRCv3 capabilities reported by make_server /
+// server_details events without removing them from the normal event flow
+func (i *IRCCloudBot) captureCapabilities(e Event) {
+	typ, _ := e["type"].(string)
+	if typ != "make_server" && typ != "server_details" {
+		return
+	}
+
+	caps := parseCaps(e["cap"])
+	if caps == nil {
+		return
+	}
+
+	i.streamMu.Lock()
+	i.capsByCID[e.ConnectionID()] = caps
+	i.streamMu.Unlock()
+}
+
+func parseCaps(raw interface{}) []string {
+	switch v := raw.(type) {
+	case map[string]interface{}:
+		caps := make([]string, 0, len(v))
+		for k := range v {
+			caps = append(caps, k)
+		}
+		sort.Strings(caps)
+		return caps
+
+	case []interface{}:
+		caps := make([]string, 0, len(v))
+		for _, c := range v {
+			if s, ok := c.(string); ok {
+				caps = append(caps, s)
+			}
+		}
+		return caps
+
+	default:
+		return nil
+	}
+}
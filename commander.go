@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+)
+
+const defaultCommandPrefix = "!"
+
+// Command is the parsed representation of a command invocation
+type Command struct {
+	Name  string
+	Args  []string
+	Rest  string
+	Event *BufferMsgEvent
+	Reply func(string) error
+}
+
+// CommandFunc handles a parsed Command
+type CommandFunc func(*Command) error
+
+// CommandMiddleware wraps a CommandFunc with additional behavior (auth, rate limiting, logging, ...)
+type CommandMiddleware func(CommandFunc) CommandFunc
+
+type commandRegistration struct {
+	fn   CommandFunc
+	help string
+	acl  *ACL
+}
+
+// Command registers fn to run whenever a buffer message starts with the
+// channel's command prefix (see SetPrefix / SetChannelPrefix, default "!")
+// followed by name
+func (i *IRCCloudBot) Command(name string, fn CommandFunc) {
+	i.initCommander()
+	i.commands[name] = &commandRegistration{fn: fn}
+}
+
+// CommandHelp sets the help text shown for name by the automatic "help" command
+func (i *IRCCloudBot) CommandHelp(name, help string) {
+	if c, ok := i.commands[name]; ok {
+		c.help = help
+	}
+}
+
+// RestrictCommand limits who may invoke name to the nicks/hostmasks allowed by acl
+func (i *IRCCloudBot) RestrictCommand(name string, acl *ACL) {
+	if c, ok := i.commands[name]; ok {
+		c.acl = acl
+	}
+}
+
+// Use registers mw as global middleware wrapping every command invocation.
+// Middleware registered first runs outermost.
+func (i *IRCCloudBot) Use(mw CommandMiddleware) {
+	i.commandMiddleware = append(i.commandMiddleware, mw)
+}
+
+// SetPrefix sets the default command prefix (defaults to "!")
+func (i *IRCCloudBot) SetPrefix(prefix string) {
+	i.defaultPrefix = prefix
+}
+
+// SetChannelPrefix overrides the command prefix for a specific channel
+func (i *IRCCloudBot) SetChannelPrefix(channel, prefix string) {
+	i.channelPrefixes[channel] = prefix
+}
+
+func (i *IRCCloudBot) initCommander() {
+	i.commanderOnce.Do(func() {
+		i.commands["help"] = &commandRegistration{fn: i.helpCommand, help: "Shows this help text"}
+		i.OnBufferMsg(i.handleCommandMessage)
+	})
+}
+
+func (i *IRCCloudBot) prefixFor(channel string) string {
+	if p, ok := i.channelPrefixes[channel]; ok {
+		return p
+	}
+	if i.defaultPrefix != "" {
+		return i.defaultPrefix
+	}
+	return defaultCommandPrefix
+}
+
+func (i *IRCCloudBot) handleCommandMessage(e *BufferMsgEvent) error {
+	if e.Self {
+		return nil
+	}
+
+	prefix := i.prefixFor(e.Channel)
+	if prefix == "" || !strings.HasPrefix(e.Message, prefix) {
+		return nil
+	}
+
+	body := strings.TrimPrefix(e.Message, prefix)
+	fields := strings.Fields(body)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	name := fields[0]
+	reg, ok := i.commands[name]
+	if !ok {
+		return nil
+	}
+
+	if !reg.acl.Allows(e.From, e.Hostmask) {
+		return nil
+	}
+
+	cmd := &Command{
+		Name:  name,
+		Args:  fields[1:],
+		Rest:  strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body), name)),
+		Event: e,
+		Reply: func(msg string) error { return e.Raw().Reply(msg) },
+	}
+
+	fn := reg.fn
+	for idx := len(i.commandMiddleware) - 1; idx >= 0; idx-- {
+		fn = i.commandMiddleware[idx](fn)
+	}
+
+	return fn(cmd)
+}
+
+func (i *IRCCloudBot) helpCommand(c *Command) error {
+	names := make([]string, 0, len(i.commands))
+	for name := range i.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prefix := i.prefixFor(c.Event.Channel)
+
+	var b strings.Builder
+	b.WriteString("Available commands: ")
+	for idx, name := range names {
+		if idx > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(prefix + name)
+		if help := i.commands[name].help; help != "" {
+			b.WriteString(" (" + help + ")")
+		}
+	}
+
+	return c.Reply(b.String())
+}
@@ -0,0 +1,162 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestBot() *IRCCloudBot {
+	return &IRCCloudBot{
+		commands:        make(map[string]*commandRegistration),
+		channelPrefixes: make(map[string]string),
+		typedHandlers:   make(map[string][]func(interface{}) error),
+	}
+}
+
+func newTestBufferMsg(from, hostmask, channel, message string) *BufferMsgEvent {
+	return &BufferMsgEvent{
+		BaseEvent: BaseEvent{Type: "buffer_msg"},
+		From:      from,
+		Hostmask:  hostmask,
+		Channel:   channel,
+		Message:   message,
+	}
+}
+
+func TestPrefixForDefaultsToBang(t *testing.T) {
+	bot := newTestBot()
+
+	if got := bot.prefixFor("#any"); got != defaultCommandPrefix {
+		t.Fatalf("expected default prefix %q, got %q", defaultCommandPrefix, got)
+	}
+}
+
+func TestPrefixForHonorsSetPrefixAndChannelOverride(t *testing.T) {
+	bot := newTestBot()
+	bot.SetPrefix("#")
+	bot.SetChannelPrefix("#special", "$")
+
+	if got := bot.prefixFor("#general"); got != "#" {
+		t.Fatalf("expected global prefix override %q, got %q", "#", got)
+	}
+	if got := bot.prefixFor("#special"); got != "$" {
+		t.Fatalf("expected channel prefix override %q, got %q", "$", got)
+	}
+}
+
+func TestHandleCommandMessageParsesArgsAndRest(t *testing.T) {
+	bot := newTestBot()
+
+	var got *Command
+	bot.Command("greet", func(c *Command) error {
+		got = c
+		return nil
+	})
+
+	e := newTestBufferMsg("alice", "alice@example.net", "#test", "!greet bob smith  please")
+	if err := bot.handleCommandMessage(e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if got == nil {
+		t.Fatal("expected the command handler to run")
+	}
+	if got.Name != "greet" {
+		t.Fatalf("expected command name %q, got %q", "greet", got.Name)
+	}
+	if want := []string{"bob", "smith", "please"}; !reflect.DeepEqual(got.Args, want) {
+		t.Fatalf("expected Args %#v, got %#v", want, got.Args)
+	}
+	if want := "bob smith  please"; got.Rest != want {
+		t.Fatalf("expected Rest %q, got %q", want, got.Rest)
+	}
+}
+
+func TestHandleCommandMessageIgnoresMessagesWithoutPrefix(t *testing.T) {
+	bot := newTestBot()
+
+	called := false
+	bot.Command("greet", func(c *Command) error {
+		called = true
+		return nil
+	})
+
+	e := newTestBufferMsg("alice", "alice@example.net", "#test", "greet bob")
+	if err := bot.handleCommandMessage(e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if called {
+		t.Fatal("expected the command handler not to run for a message without the prefix")
+	}
+}
+
+func TestHandleCommandMessageDeniesACLMismatch(t *testing.T) {
+	bot := newTestBot()
+
+	called := false
+	bot.Command("greet", func(c *Command) error {
+		called = true
+		return nil
+	})
+	bot.RestrictCommand("greet", NewACL().AllowNick("bob"))
+
+	e := newTestBufferMsg("alice", "alice@example.net", "#test", "!greet")
+	if err := bot.handleCommandMessage(e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if called {
+		t.Fatal("expected the ACL to deny alice and the command handler not to run")
+	}
+}
+
+func TestHandleCommandMessageAllowsACLMatch(t *testing.T) {
+	bot := newTestBot()
+
+	called := false
+	bot.Command("greet", func(c *Command) error {
+		called = true
+		return nil
+	})
+	bot.RestrictCommand("greet", NewACL().AllowNick("alice"))
+
+	e := newTestBufferMsg("alice", "alice@example.net", "#test", "!greet")
+	if err := bot.handleCommandMessage(e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !called {
+		t.Fatal("expected the ACL to allow alice and the command handler to run")
+	}
+}
+
+func TestUseMiddlewareRunsFirstRegisteredOutermost(t *testing.T) {
+	bot := newTestBot()
+
+	var order []string
+	bot.Command("greet", func(c *Command) error {
+		order = append(order, "fn")
+		return nil
+	})
+
+	wrap := func(name string) CommandMiddleware {
+		return func(next CommandFunc) CommandFunc {
+			return func(c *Command) error {
+				order = append(order, name+":enter")
+				err := next(c)
+				order = append(order, name+":exit")
+				return err
+			}
+		}
+	}
+	bot.Use(wrap("outer"))
+	bot.Use(wrap("inner"))
+
+	e := newTestBufferMsg("alice", "alice@example.net", "#test", "!greet")
+	if err := bot.handleCommandMessage(e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []string{"outer:enter", "inner:enter", "fn", "inner:exit", "outer:exit"}
+	if !reflect.DeepEqual(order, want) {
+		t.Fatalf("expected middleware order %#v, got %#v", want, order)
+	}
+}
@@ -27,6 +27,32 @@ func (e Event) Reply(message string) error {
 	return e.Conn().Say(int(e["cid"].(float64)), e["chan"].(string), message)
 }
 
+// ReplyMention is a shorthand to Reply that prefixes the message with a mention of the sender
+func (e Event) ReplyMention(message string) error {
+	return e.Reply(fmt.Sprintf("%s: %s", e.From(), message))
+}
+
+// ReplyAction is a shorthand to Reply that sends the message as a /me action
+func (e Event) ReplyAction(message string) error {
+	return e.Reply("/me " + message)
+}
+
+// ReplyNotice is a shorthand to Event.Conn().Say(...) that sends a NOTICE instead of a PRIVMSG to the same channel the message was received from
+func (e Event) ReplyNotice(message string) error {
+	if e["type"] != "buffer_msg" {
+		return fmt.Errorf("Cannot reply to type '%s'", e["type"].(string))
+	}
+	return e.Conn().Say(e.ConnectionID(), e.Chan(), "/notice "+e.Chan()+" "+message)
+}
+
+// ReplyPrivate is a shorthand to Event.Conn().Say(...) that always sends a DM to the sender, even if the original message was received in a channel
+func (e Event) ReplyPrivate(message string) error {
+	if e["type"] != "buffer_msg" {
+		return fmt.Errorf("Cannot reply to type '%s'", e["type"].(string))
+	}
+	return e.Conn().Say(e.ConnectionID(), e.From(), message)
+}
+
 // IsSelf returns whether the message was sent by ourselves
 func (e Event) IsSelf() bool {
 	v, err := e.Bool("self")
@@ -45,6 +71,18 @@ func (e Event) Chan() string {
 	return v
 }
 
+// Nick returns the nick the event concerns (e.g. who joined, parted or changed their name)
+func (e Event) Nick() string {
+	v, _ := e.Str("nick")
+	return v
+}
+
+// Msg returns the raw message text carried by the event, if any
+func (e Event) Msg() string {
+	v, _ := e.Str("msg")
+	return v
+}
+
 // Returns the ConnectionID the message was sent through
 func (e Event) ConnectionID() int {
 	return int(e["cid"].(float64))
@@ -0,0 +1,207 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"golang.org/x/net/context"
+	"golang.org/x/net/context/ctxhttp"
+)
+
+// Buffer describes a known IRCCloud buffer (a channel or query window)
+type Buffer struct {
+	BID  int
+	CID  int
+	Name string
+}
+
+type bufferKey struct {
+	CID  int
+	Name string
+}
+
+// HistoryOptions configures a History / HistoryIter call
+type HistoryOptions struct {
+	// Limit caps the number of events returned per page (IRCCloud's own default applies if zero)
+	Limit int
+	// Before restricts the query to events older than this event id
+	Before int
+	// After drops events older than or equal to this event id from the result
+	After int
+	// MaxPages caps how many pages HistoryIter will fetch (0 = unlimited)
+	MaxPages int
+}
+
+// Buffers returns every buffer seen so far via make_buffer / channel_init events
+func (i *IRCCloudBot) Buffers() []Buffer {
+	i.streamMu.Lock()
+	defer i.streamMu.Unlock()
+
+	out := make([]Buffer, 0, len(i.buffers))
+	for _, b := range i.buffers {
+		out = append(out, b)
+	}
+	return out
+}
+
+// Connections returns the connection id of every network whose make_server /
+// server_details event carried capability information so far; networks whose
+// events carried none are not listed
+func (i *IRCCloudBot) Connections() []int {
+	i.streamMu.Lock()
+	defer i.streamMu.Unlock()
+
+	out := make([]int, 0, len(i.capsByCID))
+	for cid := range i.capsByCID {
+		out = append(out, cid)
+	}
+	return out
+}
+
+// History fetches a single page of backlog for target (a channel or query name)
+// on connectionID from IRCCloud's /chat/backlog endpoint
+func (i *IRCCloudBot) History(connectionID int, target string, opts HistoryOptions) ([]Event, error) {
+	bid, err := i.resolveBID(connectionID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	values := url.Values{
+		"cid": []string{strconv.Itoa(connectionID)},
+		"bid": []string{strconv.Itoa(bid)},
+	}
+	if opts.Limit > 0 {
+		values.Set("num", strconv.Itoa(opts.Limit))
+	}
+	if opts.Before > 0 {
+		values.Set("beforeid", strconv.Itoa(opts.Before))
+	}
+
+	events, err := i.fetchBacklogPage(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.After > 0 {
+		events = filterAfterEID(events, opts.After)
+	}
+
+	return events, nil
+}
+
+// HistoryIter walks the backlog for target page by page, calling fn once per
+// page, until the backlog is exhausted, opts.MaxPages is reached or ctx is
+// cancelled
+func (i *IRCCloudBot) HistoryIter(ctx context.Context, connectionID int, target string, opts HistoryOptions, fn func([]Event) error) error {
+	before := opts.Before
+
+	for pages := 0; opts.MaxPages <= 0 || pages < opts.MaxPages; pages++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		page, err := i.History(connectionID, target, HistoryOptions{Limit: opts.Limit, Before: before, After: opts.After})
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+
+		next := minEID(page) - 1
+		if next <= 0 || next == before {
+			return nil
+		}
+		before = next
+	}
+
+	return nil
+}
+
+func (i *IRCCloudBot) resolveBID(connectionID int, target string) (int, error) {
+	i.streamMu.Lock()
+	b, ok := i.buffers[bufferKey{CID: connectionID, Name: target}]
+	i.streamMu.Unlock()
+
+	if !ok {
+		return 0, fmt.Errorf("no buffer known for %q on cid %d", target, connectionID)
+	}
+	return b.BID, nil
+}
+
+func (i *IRCCloudBot) fetchBacklogPage(values url.Values) ([]Event, error) {
+	req := i.getAuthenticatedRequest("GET", "/chat/backlog?"+values.Encode(), nil)
+	res, err := ctxhttp.Do(i.parentContext, i.HTTPClient, req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	events := []Event{}
+	if err := json.NewDecoder(res.Body).Decode(&events); err != nil {
+		return nil, err
+	}
+
+	for idx := range events {
+		events[idx]["_conn"] = i
+	}
+
+	return events, nil
+}
+
+// captureBuffer caches the bid/cid/name of known buffers reported by
+// make_buffer / channel_init events without removing them from the normal
+// event flow
+func (i *IRCCloudBot) captureBuffer(e Event) {
+	typ, _ := e["type"].(string)
+	if typ != "make_buffer" && typ != "channel_init" {
+		return
+	}
+
+	bid, ok := e["bid"].(float64)
+	if !ok {
+		return
+	}
+
+	name := e.Chan()
+	if name == "" {
+		name, _ = e.Str("name")
+	}
+	if name == "" {
+		return
+	}
+
+	b := Buffer{BID: int(bid), CID: e.ConnectionID(), Name: name}
+
+	i.streamMu.Lock()
+	i.buffers[bufferKey{CID: b.CID, Name: b.Name}] = b
+	i.streamMu.Unlock()
+}
+
+func filterAfterEID(events []Event, after int) []Event {
+	out := events[:0]
+	for _, e := range events {
+		if eid, ok := e["eid"].(float64); ok && int(eid) <= after {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func minEID(events []Event) int {
+	min := 0
+	for idx, e := range events {
+		eid, _ := e["eid"].(float64)
+		if idx == 0 || int(eid) < min {
+			min = int(eid)
+		}
+	}
+	return min
+}
@@ -0,0 +1,53 @@
+package bot
+
+import "testing"
+
+func TestFilterAfterEID(t *testing.T) {
+	events := []Event{
+		{"eid": float64(1)},
+		{"eid": float64(2)},
+		{"eid": float64(3)},
+	}
+
+	out := filterAfterEID(events, 1)
+
+	if len(out) != 2 {
+		t.Fatalf("expected 2 events after filtering, got %d", len(out))
+	}
+	for _, e := range out {
+		if eid := e["eid"].(float64); eid <= 1 {
+			t.Fatalf("expected all remaining events to have eid > 1, found %v", eid)
+		}
+	}
+}
+
+func TestFilterAfterEIDKeepsEventsWithoutEID(t *testing.T) {
+	events := []Event{
+		{"type": "batch"},
+		{"eid": float64(5)},
+	}
+
+	out := filterAfterEID(events, 10)
+
+	if len(out) != 1 {
+		t.Fatalf("expected the event without an eid to survive filtering, got %d events", len(out))
+	}
+}
+
+func TestMinEID(t *testing.T) {
+	events := []Event{
+		{"eid": float64(9)},
+		{"eid": float64(3)},
+		{"eid": float64(7)},
+	}
+
+	if got := minEID(events); got != 3 {
+		t.Fatalf("expected minEID to be 3, got %d", got)
+	}
+}
+
+func TestMinEIDEmpty(t *testing.T) {
+	if got := minEID(nil); got != 0 {
+		t.Fatalf("expected minEID of an empty slice to be 0, got %d", got)
+	}
+}
@@ -0,0 +1,206 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/context"
+	"golang.org/x/time/rate"
+)
+
+// SendMode controls what SendCtx / Say do once the outbound rate limit for a
+// connection is exhausted
+type SendMode int
+
+const (
+	// SendModeBlock waits until the limiter has capacity again (default)
+	SendModeBlock SendMode = iota
+	// SendModeError returns an error immediately instead of waiting
+	SendModeError
+)
+
+const (
+	maxMessageBytes  = 400
+	continuationMark = " (cont.)"
+	maxSendAttempts  = 5
+	retryBaseDelay   = 500 * time.Millisecond
+)
+
+// SendCtx behaves like Say, but allows the caller to cancel a message that is
+// still waiting on the rate limiter, the send queue or a retry backoff via ctx
+func (i *IRCCloudBot) SendCtx(ctx context.Context, connectionID int, target, message string) error {
+	lock := i.getSendLock(connectionID, target)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, chunk := range splitMessage(message, maxMessageBytes) {
+		if err := i.sendOne(ctx, connectionID, target, chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *IRCCloudBot) sendOne(ctx context.Context, connectionID int, target, message string) error {
+	return i.throttledAction(ctx, connectionID, func() error {
+		return i.say(connectionID, target, message)
+	})
+}
+
+// throttledAction waits for rate-limiter capacity on connectionID (or fails
+// immediately under SendModeError) and then runs action, retrying it with
+// exponential backoff on transient errors. Every outbound API call the bot
+// makes (Say, Join, Part, Topic, Nick, ...) is expected to go through this.
+func (i *IRCCloudBot) throttledAction(ctx context.Context, connectionID int, action func() error) error {
+	limiter := i.getLimiter(connectionID)
+
+	if i.SendMode == SendModeError {
+		if !limiter.Allow() {
+			return fmt.Errorf("rate limit exceeded for cid %d", connectionID)
+		}
+	} else if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	return i.withRetry(ctx, action)
+}
+
+func (i *IRCCloudBot) withRetry(ctx context.Context, action func() error) error {
+	delay := retryBaseDelay
+
+	var lastErr error
+	for attempt := 0; attempt < maxSendAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		err := action()
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		if _, transient := err.(*transientSendError); !transient {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+func (i *IRCCloudBot) getLimiter(connectionID int) *rate.Limiter {
+	i.sendMu.Lock()
+	defer i.sendMu.Unlock()
+
+	l, ok := i.limiters[connectionID]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(i.MessagesPerSecond), i.Burst)
+		i.limiters[connectionID] = l
+	}
+
+	return l
+}
+
+func (i *IRCCloudBot) getSendLock(connectionID int, target string) *sync.Mutex {
+	key := strconv.Itoa(connectionID) + ":" + target
+
+	i.sendMu.Lock()
+	defer i.sendMu.Unlock()
+
+	l, ok := i.sendLocks[key]
+	if !ok {
+		l = &sync.Mutex{}
+		i.sendLocks[key] = l
+	}
+
+	return l
+}
+
+// splitMessage breaks message into chunks of at most maxBytes at word
+// boundaries, appending continuationMark to every chunk but the last. A chunk
+// exceeds maxBytes only when maxBytes is too small to hold continuationMark
+// plus a single rune.
+func splitMessage(message string, maxBytes int) []string {
+	if len(message) <= maxBytes {
+		return []string{message}
+	}
+
+	limit := maxBytes - len(continuationMark)
+	if limit < 1 {
+		limit = maxBytes
+	}
+
+	var chunks []string
+	var current string
+
+	flush := func() {
+		if current != "" {
+			chunks = append(chunks, current)
+			current = ""
+		}
+	}
+
+	for _, word := range strings.Fields(message) {
+		for len(word) > limit {
+			flush()
+			head, rest := cutRuneBoundary(word, limit)
+			chunks = append(chunks, head)
+			word = rest
+		}
+
+		switch {
+		case current == "":
+			current = word
+		case len(current)+1+len(word) > limit:
+			flush()
+			current = word
+		default:
+			current += " " + word
+		}
+	}
+	flush()
+
+	for idx := range chunks[:len(chunks)-1] {
+		chunks[idx] += continuationMark
+	}
+
+	return chunks
+}
+
+// cutRuneBoundary splits s into the longest valid-UTF8 prefix of at most limit
+// bytes and the remainder, never cutting a multi-byte rune in half. If even
+// the first rune of s is longer than limit, it is kept whole regardless.
+func cutRuneBoundary(s string, limit int) (head, rest string) {
+	if len(s) <= limit {
+		return s, ""
+	}
+
+	end := limit
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+
+	if end == 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		if size == 0 {
+			size = 1
+		}
+		return s[:size], s[size:]
+	}
+
+	return s[:end], s[end:]
+}
@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMessageShortMessageUnchanged(t *testing.T) {
+	msg := "ohai!"
+	chunks := splitMessage(msg, maxMessageBytes)
+	if len(chunks) != 1 || chunks[0] != msg {
+		t.Fatalf("expected message to pass through unchanged, got %#v", chunks)
+	}
+}
+
+func TestSplitMessageWordBoundary(t *testing.T) {
+	msg := strings.Repeat("word ", 100)
+	chunks := splitMessage(msg, 20)
+
+	for idx, c := range chunks {
+		if len(c) > 20 {
+			t.Fatalf("chunk %d exceeds limit: %q (%d bytes)", idx, c, len(c))
+		}
+		if idx < len(chunks)-1 && !strings.HasSuffix(c, continuationMark) {
+			t.Fatalf("chunk %d is missing continuation marker: %q", idx, c)
+		}
+	}
+	if strings.HasSuffix(chunks[len(chunks)-1], continuationMark) {
+		t.Fatalf("last chunk should not carry a continuation marker: %q", chunks[len(chunks)-1])
+	}
+}
+
+func TestSplitMessageOversizedWordIsValidUTF8(t *testing.T) {
+	// A run of multi-byte runes longer than the limit must never be cut mid-rune
+	msg := strings.Repeat("あ", 20)
+
+	chunks := splitMessage(msg, 10)
+
+	for idx, c := range chunks {
+		plain := strings.TrimSuffix(c, continuationMark)
+		if !utf8.ValidString(plain) {
+			t.Fatalf("chunk %d is not valid UTF-8: %q", idx, plain)
+		}
+	}
+
+	var rebuilt strings.Builder
+	for _, c := range chunks {
+		rebuilt.WriteString(strings.TrimSuffix(c, continuationMark))
+	}
+	if rebuilt.String() != msg {
+		t.Fatalf("chunks do not reassemble to the original message: got %q, want %q", rebuilt.String(), msg)
+	}
+}
+
+func TestCutRuneBoundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		limit int
+	}{
+		{"ascii", "hello world", 5},
+		{"multibyte", strings.Repeat("あ", 5), 4},
+		{"single-rune-over-limit", "あ", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			head, rest := cutRuneBoundary(tt.s, tt.limit)
+			if !utf8.ValidString(head) {
+				t.Fatalf("head is not valid UTF-8: %q", head)
+			}
+			if !utf8.ValidString(rest) {
+				t.Fatalf("rest is not valid UTF-8: %q", rest)
+			}
+			if head+rest != tt.s {
+				t.Fatalf("head+rest does not reconstruct s: got %q+%q, want %q", head, rest, tt.s)
+			}
+		})
+	}
+}
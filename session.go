@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/context/ctxhttp"
+)
+
+const defaultSessionRefreshInterval = 15 * time.Minute
+
+// validateSessionLoop periodically checks the session is still valid and
+// transparently re-logs in if it is not, so long-running bots don't die once
+// the cookie expires. It is started exactly once per IRCCloudBot by Start.
+func (i *IRCCloudBot) validateSessionLoop() {
+	interval := i.SessionRefreshInterval
+	if interval <= 0 {
+		interval = defaultSessionRefreshInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.parentContext.Done():
+			return
+		case <-ticker.C:
+			if err := i.refreshSession(); err != nil {
+				log.Printf("Could not refresh IRCCloud session: %s", err)
+			}
+		}
+	}
+}
+
+// refreshSession checks the current session against /chat/session and
+// transparently re-logs in if it has expired
+func (i *IRCCloudBot) refreshSession() error {
+	req := i.getAuthenticatedRequest("GET", "/chat/session", nil)
+	res, err := ctxhttp.Do(i.parentContext, i.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusUnauthorized {
+		return i.login()
+	}
+
+	r := map[string]interface{}{}
+	if err := json.NewDecoder(res.Body).Decode(&r); err != nil {
+		return err
+	}
+
+	if success, ok := r["success"].(bool); !ok || !success {
+		return i.login()
+	}
+
+	return nil
+}
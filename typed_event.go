@@ -0,0 +1,289 @@
+package bot
+
+import "time"
+
+// BaseEvent carries the fields common to every typed event, including the
+// IRCv3 message-tag metadata (time, msgid, account, batch, ...) IRCCloud
+// forwards for networks that advertise the corresponding capability.
+type BaseEvent struct {
+	Type string
+	CID  int
+	EID  float64
+
+	// Tags holds the raw IRCv3 message tags attached to the event, if any
+	Tags map[string]string
+	// Time is the IRCv3 server-time capability's "time" tag, falling back to
+	// the eid and finally to the time the event was received if neither is present
+	Time time.Time
+
+	raw Event
+}
+
+// Raw returns the untyped Event this typed event was decoded from
+func (b BaseEvent) Raw() Event { return b.raw }
+
+// BufferMsgEvent represents a message posted to a buffer (channel or query window)
+type BufferMsgEvent struct {
+	BaseEvent
+
+	From string
+	// Hostmask is the sender's bare "user@host" (no "nick!" prefix), as
+	// reported by IRCCloud's from_host field
+	Hostmask string
+	Channel  string
+	Message  string
+	Self     bool
+}
+
+// JoinEvent represents a user joining a channel
+type JoinEvent struct {
+	BaseEvent
+
+	Nick    string
+	Channel string
+	// Hostmask is the full "nick!user@host" reported for the join
+	Hostmask string
+}
+
+// PartEvent represents a user leaving a channel
+type PartEvent struct {
+	BaseEvent
+
+	Nick    string
+	Channel string
+	Message string
+}
+
+// NickChangeEvent represents a user changing their nickname
+type NickChangeEvent struct {
+	BaseEvent
+
+	OldNick string
+	NewNick string
+}
+
+// ChannelInitEvent represents the initial state IRCCloud sends for a channel the bot is present in
+type ChannelInitEvent struct {
+	BaseEvent
+
+	Channel string
+	Topic   string
+}
+
+// HeartbeatEvent is periodically sent by IRCCloud to confirm the stream is still alive
+type HeartbeatEvent struct {
+	BaseEvent
+}
+
+// RegisterTypedHandler registers h to be called whenever an event of type eventType
+// is decoded into a *T. Only the event types handled by the On... methods on
+// IRCCloudBot are decoded, each into its own struct; a handler registered for
+// any other event type, or with a T other than that struct, is never called.
+func RegisterTypedHandler[T any](i *IRCCloudBot, eventType string, h func(*T) error) {
+	i.typedHandlers[eventType] = append(i.typedHandlers[eventType], func(te interface{}) error {
+		v, ok := te.(*T)
+		if !ok {
+			return nil
+		}
+		return h(v)
+	})
+}
+
+// OnBufferMsg registers h to be called for every decoded BufferMsgEvent
+func (i *IRCCloudBot) OnBufferMsg(h func(*BufferMsgEvent) error) {
+	RegisterTypedHandler(i, "buffer_msg", h)
+}
+
+// OnJoin registers h to be called for every decoded JoinEvent
+func (i *IRCCloudBot) OnJoin(h func(*JoinEvent) error) {
+	RegisterTypedHandler(i, "joined_channel", h)
+}
+
+// OnPart registers h to be called for every decoded PartEvent
+func (i *IRCCloudBot) OnPart(h func(*PartEvent) error) {
+	RegisterTypedHandler(i, "parted_channel", h)
+}
+
+// OnNickChange registers h to be called for every decoded NickChangeEvent
+func (i *IRCCloudBot) OnNickChange(h func(*NickChangeEvent) error) {
+	RegisterTypedHandler(i, "nickchange", h)
+}
+
+// OnChannelInit registers h to be called for every decoded ChannelInitEvent
+func (i *IRCCloudBot) OnChannelInit(h func(*ChannelInitEvent) error) {
+	RegisterTypedHandler(i, "channel_init", h)
+}
+
+// OnHeartbeat registers h to be called for every decoded HeartbeatEvent
+func (i *IRCCloudBot) OnHeartbeat(h func(*HeartbeatEvent) error) {
+	RegisterTypedHandler(i, "idle", h)
+}
+
+// dispatchTypedEvent decodes e into its typed representation (if one is known for
+// e's type) and fans it out to the handlers registered for that type
+func (i *IRCCloudBot) dispatchTypedEvent(e Event) error {
+	typ, _ := e["type"].(string)
+
+	hs, ok := i.typedHandlers[typ]
+	if !ok || len(hs) == 0 {
+		return nil
+	}
+
+	te := decodeTypedEvent(typ, e)
+	if te == nil {
+		return nil
+	}
+
+	for _, h := range hs {
+		if err := h(te); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeTypedEvent(typ string, e Event) interface{} {
+	switch typ {
+	case "buffer_msg":
+		return decodeBufferMsgEvent(e)
+	case "joined_channel":
+		return decodeJoinEvent(e)
+	case "parted_channel":
+		return decodePartEvent(e)
+	case "nickchange":
+		return decodeNickChangeEvent(e)
+	case "channel_init":
+		return decodeChannelInitEvent(e)
+	case "idle":
+		return decodeHeartbeatEvent(e)
+	default:
+		return nil
+	}
+}
+
+func newBaseEvent(e Event) BaseEvent {
+	typ, _ := e["type"].(string)
+
+	var cid int
+	if _, ok := e["cid"]; ok {
+		cid = e.ConnectionID()
+	}
+
+	var eid float64
+	if v, ok := e["eid"].(float64); ok {
+		eid = v
+	}
+
+	tags := parseTags(e)
+
+	return BaseEvent{
+		Type: typ,
+		CID:  cid,
+		EID:  eid,
+		Tags: tags,
+		Time: parseEventTime(eid, tags),
+		raw:  e,
+	}
+}
+
+// parseTags extracts the IRCv3 message tags IRCCloud attaches to the raw event, if any
+func parseTags(e Event) map[string]string {
+	raw, ok := e["tags"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	tags := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if s, ok := v.(string); ok {
+			tags[k] = s
+		}
+	}
+
+	return tags
+}
+
+// parseEventTime prefers the IRCv3 server-time capability's "time" tag, falls
+// back to the eid (a microsecond timestamp assigned by IRCCloud) and finally
+// to the receive time
+func parseEventTime(eid float64, tags map[string]string) time.Time {
+	if ts, ok := tags["time"]; ok {
+		if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+			return t
+		}
+	}
+
+	if eid > 0 {
+		return time.Unix(0, int64(eid)*1000)
+	}
+
+	return time.Now()
+}
+
+func decodeBufferMsgEvent(e Event) *BufferMsgEvent {
+	self, _ := e.Bool("self")
+	hostmask, _ := e.Str("from_host")
+
+	return &BufferMsgEvent{
+		BaseEvent: newBaseEvent(e),
+		From:      e.From(),
+		Hostmask:  hostmask,
+		Channel:   e.Chan(),
+		Message:   e.Msg(),
+		Self:      self,
+	}
+}
+
+func decodeJoinEvent(e Event) *JoinEvent {
+	hostmask, _ := e.Str("hostmask")
+
+	return &JoinEvent{
+		BaseEvent: newBaseEvent(e),
+		Nick:      e.Nick(),
+		Channel:   e.Chan(),
+		Hostmask:  hostmask,
+	}
+}
+
+func decodePartEvent(e Event) *PartEvent {
+	return &PartEvent{
+		BaseEvent: newBaseEvent(e),
+		Nick:      e.Nick(),
+		Channel:   e.Chan(),
+		Message:   e.Msg(),
+	}
+}
+
+func decodeNickChangeEvent(e Event) *NickChangeEvent {
+	oldNick, _ := e.Str("oldnick")
+	newNick, _ := e.Str("newnick")
+
+	return &NickChangeEvent{
+		BaseEvent: newBaseEvent(e),
+		OldNick:   oldNick,
+		NewNick:   newNick,
+	}
+}
+
+func decodeChannelInitEvent(e Event) *ChannelInitEvent {
+	topic := ""
+	switch t := e["topic"].(type) {
+	case string:
+		topic = t
+	case map[string]interface{}:
+		if s, ok := t["text"].(string); ok {
+			topic = s
+		}
+	}
+
+	return &ChannelInitEvent{
+		BaseEvent: newBaseEvent(e),
+		Channel:   e.Chan(),
+		Topic:     topic,
+	}
+}
+
+func decodeHeartbeatEvent(e Event) *HeartbeatEvent {
+	return &HeartbeatEvent{BaseEvent: newBaseEvent(e)}
+}
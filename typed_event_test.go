@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTags(t *testing.T) {
+	e := Event{
+		"tags": map[string]interface{}{
+			"msgid":        "abc123",
+			"time":         "2026-01-01T00:00:00.000Z",
+			"not-a-string": 42,
+		},
+	}
+
+	tags := parseTags(e)
+
+	if tags["msgid"] != "abc123" {
+		t.Fatalf("expected msgid tag to survive, got %#v", tags)
+	}
+	if _, ok := tags["not-a-string"]; ok {
+		t.Fatalf("expected non-string tag values to be dropped, got %#v", tags)
+	}
+}
+
+func TestParseTagsMissing(t *testing.T) {
+	if tags := parseTags(Event{}); tags != nil {
+		t.Fatalf("expected nil tags when the event carries none, got %#v", tags)
+	}
+}
+
+func TestParseEventTimePrefersServerTime(t *testing.T) {
+	want := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	tags := map[string]string{"time": want.Format(time.RFC3339Nano)}
+
+	got := parseEventTime(0, tags)
+
+	if !got.Equal(want) {
+		t.Fatalf("expected parseEventTime to use the time tag, got %v want %v", got, want)
+	}
+}
+
+func TestParseEventTimeFallsBackToEID(t *testing.T) {
+	// eid is documented as a microsecond timestamp
+	eid := float64(1700000000000000)
+
+	got := parseEventTime(eid, nil)
+	want := time.Unix(0, int64(eid)*1000)
+
+	if !got.Equal(want) {
+		t.Fatalf("expected parseEventTime to derive time from eid, got %v want %v", got, want)
+	}
+}
+
+func TestParseEventTimeFallsBackToNow(t *testing.T) {
+	before := time.Now()
+	got := parseEventTime(0, nil)
+	after := time.Now()
+
+	if got.Before(before) || got.After(after) {
+		t.Fatalf("expected parseEventTime to fall back to the current time, got %v", got)
+	}
+}